@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalConfig holds configuration for the local-filesystem media backend.
+type LocalConfig struct {
+	Dir     string // directory media files are written to
+	Addr    string // address the embedded HTTP server listens on
+	BaseURL string // public URL prefix files are served under
+}
+
+// getLocalConfig returns local-filesystem store configuration from
+// environment variables.
+func getLocalConfig() *LocalConfig {
+	loadEnvFiles()
+
+	return &LocalConfig{
+		Dir:     getEnvOrDefault("LOCAL_MEDIA_DIR", "media"),
+		Addr:    getEnvOrDefault("LOCAL_MEDIA_ADDR", ":8081"),
+		BaseURL: getEnvOrDefault("LOCAL_MEDIA_BASE_URL", "http://localhost:8081"),
+	}
+}
+
+// localStore is the MediaStore backend for self-hosted setups without any
+// object storage: files are written to disk and served by an embedded HTTP
+// file server.
+type localStore struct {
+	config *LocalConfig
+}
+
+var localServerOnce sync.Once
+
+// newLocalStore builds the local-filesystem MediaStore and starts its
+// embedded HTTP server the first time it's called.
+func newLocalStore() (*localStore, error) {
+	config := getLocalConfig()
+
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local media directory: %v", err)
+	}
+
+	var serveErr error
+	localServerOnce.Do(func() {
+		serveErr = startLocalMediaServer(config)
+	})
+	if serveErr != nil {
+		return nil, serveErr
+	}
+
+	return &localStore{config: config}, nil
+}
+
+// startLocalMediaServer starts serving config.Dir over HTTP in the
+// background on config.Addr.
+func startLocalMediaServer(config *LocalConfig) error {
+	handler := http.FileServer(http.Dir(config.Dir))
+	go func() {
+		if err := http.ListenAndServe(config.Addr, handler); err != nil {
+			fmt.Printf("local media server stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// resolveKey joins key onto the configured media directory and verifies the
+// result doesn't escape it, rejecting an absolute or ".."-containing key
+// (e.g. a crafted UserID/ChatJID) that would otherwise let callers read or
+// write files anywhere the process can reach.
+func (l *localStore) resolveKey(key string) (string, error) {
+	dest := filepath.Join(l.config.Dir, filepath.FromSlash(key))
+
+	base, err := filepath.Abs(l.config.Dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve local media directory: %v", err)
+	}
+	destAbs, err := filepath.Abs(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve local media path: %v", err)
+	}
+	if destAbs != base && !strings.HasPrefix(destAbs, base+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid media key %q escapes the local media directory", key)
+	}
+	return destAbs, nil
+}
+
+// Put writes r to disk under key, relative to the configured media
+// directory. The local backend has no object metadata store, so
+// opts.ContentType and opts.Metadata are not persisted beyond what the
+// embedded file server infers from the file extension.
+func (l *localStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (string, error) {
+	dest, err := l.resolveKey(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("failed to create local media directory: %v", err)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create local media file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", fmt.Errorf("failed to write local media file: %v", err)
+	}
+
+	return strings.TrimRight(l.config.BaseURL, "/") + "/" + key, nil
+}
+
+// Exists reports whether key is already present in the local media directory.
+func (l *localStore) Exists(ctx context.Context, key string) (bool, error) {
+	dest, err := l.resolveKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(dest)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check for existing local media file: %v", err)
+	}
+	return true, nil
+}
+
+// UpdateMetadata is a no-op: the local backend has no object metadata
+// store, so a deduplicated upload keeps whatever chat-jid/sender/message-id
+// was recorded by the first upload to this key.
+func (l *localStore) UpdateMetadata(ctx context.Context, key string, opts PutOptions) error {
+	return nil
+}
+
+// Presign returns the plain served URL for key and ignores ttl; the local
+// backend has no concept of expiring links, and the embedded file server
+// serves every file under the media directory without authentication.
+func (l *localStore) Presign(key string, ttl time.Duration) (string, error) {
+	return strings.TrimRight(l.config.BaseURL, "/") + "/" + key, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreResolveKeyRejectsEscapes(t *testing.T) {
+	store := &localStore{config: &LocalConfig{Dir: t.TempDir()}}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"parent directory traversal", "../../etc/passwd"},
+		{"traversal buried in a folder segment", "user/../../../../etc/passwd"},
+		{"traversal via a crafted chat JID", "user-1/../../secrets/chat-123/abc-file.webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.resolveKey(tt.key); err == nil {
+				t.Errorf("resolveKey(%q) = nil error, want an error rejecting the escape", tt.key)
+			}
+		})
+	}
+}
+
+func TestLocalStoreResolveKeyAllowsNestedKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := &localStore{config: &LocalConfig{Dir: dir}}
+
+	got, err := store.resolveKey("user-1/chat-123/abc-sticker.webp")
+	if err != nil {
+		t.Fatalf("resolveKey returned error for a legitimate key: %v", err)
+	}
+
+	want := filepath.Join(dir, "user-1", "chat-123", "abc-sticker.webp")
+	if got != want {
+		t.Errorf("resolveKey = %q, want %q", got, want)
+	}
+}
+
+func TestLocalStorePutRejectsEscapingKey(t *testing.T) {
+	dir := t.TempDir()
+	store := &localStore{config: &LocalConfig{Dir: dir}}
+
+	if _, err := store.Put(context.Background(), "../outside.txt", strings.NewReader("data"), PutOptions{}); err == nil {
+		t.Fatal("Put with an escaping key succeeded, want an error")
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "outside.txt")); err == nil {
+		t.Fatal("Put wrote a file outside the configured media directory")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// PutOptions carries the metadata a MediaStore attaches to an uploaded
+// object, beyond the raw bytes and key.
+type PutOptions struct {
+	ContentType        string
+	ContentDisposition string
+	Metadata           map[string]string
+}
+
+// MediaStore abstracts the backend used to archive WhatsApp media (images,
+// videos, voice notes, stickers, documents). Implementations live in
+// s3.go, minio_store.go, webdav_store.go and local_store.go.
+type MediaStore interface {
+	// Put uploads the contents read from r under key and returns a URL the
+	// caller can use to retrieve the object.
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (string, error)
+
+	// Exists reports whether an object is already stored under key, so
+	// callers can skip re-uploading identical content.
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// UpdateMetadata replaces the content-type, content-disposition and
+	// metadata of an object already stored under key, without re-uploading
+	// its bytes. This lets a deduplicated upload (same hash, new message)
+	// still record the latest chat-jid/sender/message-id/timestamp.
+	// Backends with no object-metadata concept may treat this as a no-op.
+	UpdateMetadata(ctx context.Context, key string, opts PutOptions) error
+
+	// Presign returns a time-limited URL for an object previously stored
+	// under key. Backends that always serve public or unsigned URLs may
+	// just return that URL unchanged.
+	Presign(key string, ttl time.Duration) (string, error)
+}
+
+// NewMediaStore builds the MediaStore selected by the MEDIA_STORE env var.
+// Supported values are "s3" (default, for backward compatibility), "minio",
+// "webdav" and "local".
+func NewMediaStore() (MediaStore, error) {
+	switch backend := getEnvOrDefault("MEDIA_STORE", "s3"); backend {
+	case "s3":
+		return newS3Store(), nil
+	case "minio":
+		return newMinioStore()
+	case "webdav":
+		return newWebDAVStore()
+	case "local":
+		return newLocalStore()
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE backend %q (want s3, minio, webdav or local)", backend)
+	}
+}
+
+// UploadMetadata carries the WhatsApp message context needed to
+// content-address, deduplicate and tag an uploaded media file.
+type UploadMetadata struct {
+	UserID    string
+	ChatJID   string
+	Sender    string
+	MessageID string
+	Timestamp time.Time
+}
+
+// uploadToS3 uploads a file to the configured MediaStore and returns its URL.
+// The name is kept for backward compatibility with existing callers even
+// though the storage backend is no longer necessarily S3.
+//
+// The object key is content-addressed from a SHA-256 of the file so that
+// WhatsApp forwards of the same sticker or image are stored once: if the key
+// already exists, the existing object's URL is returned without uploading
+// again.
+func uploadToS3(filePath string, meta UploadMetadata) (string, error) {
+	store, err := NewMediaStore()
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize media store: %v", err)
+	}
+
+	return uploadMedia(store, filePath, meta)
+}
+
+// uploadMedia contains uploadToS3's logic against an already-built
+// MediaStore, split out so it can be exercised in tests with a fake store.
+func uploadMedia(store MediaStore, filePath string, meta UploadMetadata) (string, error) {
+	hash, err := sha256File(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	filename := filepath.Base(filePath)
+	key := fmt.Sprintf("%s%s-%s", generateFolderPath(meta.UserID, meta.ChatJID), hash, filename)
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+
+	contentType, err := detectContentType(f, filename)
+	if err != nil {
+		return "", err
+	}
+
+	opts := PutOptions{
+		ContentType:        contentType,
+		ContentDisposition: fmt.Sprintf(`attachment; filename="%s"`, filename),
+		Metadata: map[string]string{
+			"chat-jid":   meta.ChatJID,
+			"sender":     meta.Sender,
+			"message-id": meta.MessageID,
+			"timestamp":  strconv.FormatInt(meta.Timestamp.Unix(), 10),
+		},
+	}
+
+	ctx := context.Background()
+	exists, err := store.Exists(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to check for existing object: %v", err)
+	}
+	if exists {
+		// Same bytes, but a new message: refresh the metadata so the
+		// archive's index reflects this forward, not just the first upload.
+		if err := store.UpdateMetadata(ctx, key, opts); err != nil {
+			return "", fmt.Errorf("failed to update metadata on existing object: %v", err)
+		}
+		return store.Presign(key, 0)
+	}
+
+	return store.Put(ctx, key, f, opts)
+}
+
+// sha256File returns the hex-encoded SHA-256 digest of the file at path.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// detectContentType sniffs the content type from the first 512 bytes of f,
+// falling back to the extension of filename, then seeks f back to the start
+// so it can still be uploaded in full.
+func detectContentType(f *os.File, filename string) (string, error) {
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file for content-type detection: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek file: %v", err)
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if contentType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(filename)); byExt != "" {
+			contentType = byExt
+		}
+	}
+	return contentType, nil
+}
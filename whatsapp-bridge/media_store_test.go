@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeMediaStore is an in-memory MediaStore used to exercise uploadMedia
+// without a live S3/MinIO/WebDAV backend.
+type fakeMediaStore struct {
+	exists              map[string]bool
+	putCalls            int
+	putKey              string
+	putOpts             PutOptions
+	putErr              error
+	updateMetadataCalls int
+	updateMetadataKey   string
+	updateMetadataOpts  PutOptions
+	presigned           string
+}
+
+func (f *fakeMediaStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (string, error) {
+	f.putCalls++
+	f.putKey = key
+	f.putOpts = opts
+	if f.putErr != nil {
+		return "", f.putErr
+	}
+	return "https://store.example/" + key, nil
+}
+
+func (f *fakeMediaStore) Exists(ctx context.Context, key string) (bool, error) {
+	return f.exists[key], nil
+}
+
+func (f *fakeMediaStore) UpdateMetadata(ctx context.Context, key string, opts PutOptions) error {
+	f.updateMetadataCalls++
+	f.updateMetadataKey = key
+	f.updateMetadataOpts = opts
+	return nil
+}
+
+func (f *fakeMediaStore) Presign(key string, ttl time.Duration) (string, error) {
+	return f.presigned, nil
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	return path
+}
+
+func TestSha256File(t *testing.T) {
+	data := []byte("hello whatsapp")
+	path := writeTempFile(t, "sticker.webp", data)
+
+	want := sha256.Sum256(data)
+	got, err := sha256File(path)
+	if err != nil {
+		t.Fatalf("sha256File returned error: %v", err)
+	}
+	if got != hex.EncodeToString(want[:]) {
+		t.Errorf("sha256File(%q) = %q, want %q", path, got, hex.EncodeToString(want[:]))
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		filename string
+		want     string
+	}{
+		{
+			name:     "sniffed from PNG magic bytes",
+			data:     []byte("\x89PNG\r\n\x1a\n" + "rest of file"),
+			filename: "image.bin",
+			want:     "image/png",
+		},
+		{
+			name:     "falls back to extension when sniff can't identify binary data",
+			data:     []byte{0x00, 0x01, 0x02, 0x03, 0xFF, 0xFE},
+			filename: "config.json",
+			want:     "application/json",
+		},
+		{
+			name:     "octet-stream when neither sniff nor extension resolve",
+			data:     []byte{0x00, 0x01, 0x02, 0x03, 0xFF, 0xFE},
+			filename: "file.unknownext",
+			want:     "application/octet-stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, tt.filename, tt.data)
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("failed to open temp file: %v", err)
+			}
+			defer f.Close()
+
+			got, err := detectContentType(f, tt.filename)
+			if err != nil {
+				t.Fatalf("detectContentType returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectContentType(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+
+			// detectContentType must leave the file positioned at the start
+			// so the caller can still upload it in full.
+			pos, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				t.Fatalf("failed to check file position: %v", err)
+			}
+			if pos != 0 {
+				t.Errorf("detectContentType left file offset at %d, want 0", pos)
+			}
+		})
+	}
+}
+
+func TestUploadMediaKeyFormat(t *testing.T) {
+	data := []byte("duplicate sticker bytes")
+	path := writeTempFile(t, "sticker.webp", data)
+
+	hash := sha256.Sum256(data)
+	wantKey := fmt.Sprintf("user-1/chat-123/%s-sticker.webp", hex.EncodeToString(hash[:]))
+
+	store := &fakeMediaStore{exists: map[string]bool{}}
+	meta := UploadMetadata{UserID: "user-1", ChatJID: "chat:123", Sender: "alice", MessageID: "msg-1", Timestamp: time.Unix(1700000000, 0)}
+
+	if _, err := uploadMedia(store, path, meta); err != nil {
+		t.Fatalf("uploadMedia returned error: %v", err)
+	}
+
+	if store.putCalls != 1 {
+		t.Fatalf("Put called %d times, want 1", store.putCalls)
+	}
+	if store.putKey != wantKey {
+		t.Errorf("Put key = %q, want %q", store.putKey, wantKey)
+	}
+	if store.putOpts.Metadata["chat-jid"] != meta.ChatJID || store.putOpts.Metadata["sender"] != meta.Sender {
+		t.Errorf("Put metadata = %+v, missing expected chat-jid/sender", store.putOpts.Metadata)
+	}
+}
+
+func TestUploadMediaSkipsPutWhenKeyExists(t *testing.T) {
+	data := []byte("already uploaded sticker")
+	path := writeTempFile(t, "sticker.webp", data)
+
+	hash := sha256.Sum256(data)
+	key := fmt.Sprintf("user-1/chat-123/%s-sticker.webp", hex.EncodeToString(hash[:]))
+
+	store := &fakeMediaStore{
+		exists:    map[string]bool{key: true},
+		presigned: "https://store.example/" + key + "?presigned=1",
+	}
+	meta := UploadMetadata{UserID: "user-1", ChatJID: "chat:123", Sender: "bob", MessageID: "msg-2", Timestamp: time.Unix(1700000001, 0)}
+
+	got, err := uploadMedia(store, path, meta)
+	if err != nil {
+		t.Fatalf("uploadMedia returned error: %v", err)
+	}
+
+	if store.putCalls != 0 {
+		t.Errorf("Put called %d times, want 0 for a deduplicated key", store.putCalls)
+	}
+	if got != store.presigned {
+		t.Errorf("uploadMedia returned %q, want presigned URL %q", got, store.presigned)
+	}
+
+	// A forward of already-stored bytes must still refresh the per-message
+	// metadata, otherwise an index query by this message's sender/message-id
+	// would never find the (deduplicated) object.
+	if store.updateMetadataCalls != 1 {
+		t.Fatalf("UpdateMetadata called %d times, want 1", store.updateMetadataCalls)
+	}
+	if store.updateMetadataKey != key {
+		t.Errorf("UpdateMetadata key = %q, want %q", store.updateMetadataKey, key)
+	}
+	if store.updateMetadataOpts.Metadata["sender"] != meta.Sender || store.updateMetadataOpts.Metadata["message-id"] != meta.MessageID {
+		t.Errorf("UpdateMetadata opts = %+v, want refreshed sender/message-id for this forward", store.updateMetadataOpts.Metadata)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// MinioConfig holds configuration for a MinIO or other S3-compatible backend.
+type MinioConfig struct {
+	Endpoint         string
+	AccessKeyID      string
+	SecretAccessKey  string
+	BucketName       string
+	UseSSL           bool
+	SSECKey          string // SSE-C customer key, base64 or raw 32 bytes
+	UseSSES3         bool   // server-side encryption with server-managed keys
+	AutoCreateBucket bool
+}
+
+// getMinioConfig returns MinIO configuration from environment variables.
+func getMinioConfig() *MinioConfig {
+	loadEnvFiles()
+
+	return &MinioConfig{
+		Endpoint:         getEnvOrDefault("MINIO_ENDPOINT", "localhost:9000"),
+		AccessKeyID:      os.Getenv("MINIO_ACCESS_KEY_ID"),
+		SecretAccessKey:  os.Getenv("MINIO_SECRET_ACCESS_KEY"),
+		BucketName:       getEnvOrDefault("MINIO_BUCKET", "whatsapp-stuff"),
+		UseSSL:           getEnvOrDefault("MINIO_USE_SSL", "false") == "true",
+		SSECKey:          os.Getenv("MINIO_SSE_C_KEY"),
+		UseSSES3:         getEnvOrDefault("MINIO_SSE_S3", "false") == "true",
+		AutoCreateBucket: getEnvOrDefault("MINIO_AUTO_CREATE_BUCKET", "false") == "true",
+	}
+}
+
+// minioStore is the MediaStore backend for MinIO and other S3-compatible
+// servers, addressed path-style rather than virtual-hosted-style.
+type minioStore struct {
+	config *MinioConfig
+	client *minio.Client
+}
+
+// newMinioStore builds the MinIO-backed MediaStore.
+func newMinioStore() (*minioStore, error) {
+	config := getMinioConfig()
+
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+		Secure: config.UseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
+	}
+
+	return &minioStore{config: config, client: client}, nil
+}
+
+func (m *minioStore) sse() (encrypt.ServerSide, error) {
+	switch {
+	case m.config.SSECKey != "":
+		key, err := base64.StdEncoding.DecodeString(m.config.SSECKey)
+		if err != nil {
+			key = []byte(m.config.SSECKey)
+		}
+		return encrypt.NewSSEC(key)
+	case m.config.UseSSES3:
+		return encrypt.NewSSE(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// Put uploads r to the configured bucket under key, creating the bucket
+// first if MINIO_AUTO_CREATE_BUCKET is enabled.
+func (m *minioStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (string, error) {
+	if m.config.AutoCreateBucket {
+		exists, err := m.client.BucketExists(ctx, m.config.BucketName)
+		if err != nil {
+			return "", fmt.Errorf("failed to check MinIO bucket: %v", err)
+		}
+		if !exists {
+			if err := m.client.MakeBucket(ctx, m.config.BucketName, minio.MakeBucketOptions{}); err != nil {
+				return "", fmt.Errorf("failed to create MinIO bucket: %v", err)
+			}
+		}
+	}
+
+	sse, err := m.sse()
+	if err != nil {
+		return "", fmt.Errorf("failed to build server-side encryption: %v", err)
+	}
+
+	userMetadata := make(map[string]string, len(opts.Metadata))
+	for k, v := range opts.Metadata {
+		userMetadata[k] = v
+	}
+
+	_, err = m.client.PutObject(ctx, m.config.BucketName, key, r, -1, minio.PutObjectOptions{
+		ContentType:          opts.ContentType,
+		ContentDisposition:   opts.ContentDisposition,
+		ServerSideEncryption: sse,
+		UserMetadata:         userMetadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to MinIO: %v", err)
+	}
+
+	scheme := "http"
+	if m.config.UseSSL {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", scheme, m.config.Endpoint, m.config.BucketName, url.PathEscape(key)), nil
+}
+
+// Exists reports whether key is already present in the bucket.
+func (m *minioStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := m.client.StatObject(ctx, m.config.BucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		if errResp := minio.ToErrorResponse(err); errResp.Code == "NoSuchKey" || errResp.Code == "NotFound" {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check for existing MinIO object: %v", err)
+	}
+	return true, nil
+}
+
+// UpdateMetadata refreshes the content-type, content-disposition and
+// metadata of key in place via a same-bucket CopyObject with ReplaceMetadata
+// set, without re-uploading the object's bytes.
+func (m *minioStore) UpdateMetadata(ctx context.Context, key string, opts PutOptions) error {
+	sse, err := m.sse()
+	if err != nil {
+		return fmt.Errorf("failed to build server-side encryption: %v", err)
+	}
+
+	userMetadata := map[string]string{
+		"Content-Type":        opts.ContentType,
+		"Content-Disposition": opts.ContentDisposition,
+	}
+	for k, v := range opts.Metadata {
+		userMetadata[k] = v
+	}
+
+	_, err = m.client.CopyObject(ctx,
+		minio.CopyDestOptions{
+			Bucket:          m.config.BucketName,
+			Object:          key,
+			Encryption:      sse,
+			UserMetadata:    userMetadata,
+			ReplaceMetadata: true,
+		},
+		minio.CopySrcOptions{
+			Bucket: m.config.BucketName,
+			Object: key,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh MinIO object metadata: %v", err)
+	}
+	return nil
+}
+
+// Presign returns a time-limited GET URL for key. A non-positive ttl falls
+// back to defaultPresignTTL, since minio-go rejects anything under 1 second.
+func (m *minioStore) Presign(key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = defaultPresignTTL
+	}
+
+	u, err := m.client.PresignedGetObject(context.Background(), m.config.BucketName, key, ttl, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign MinIO URL: %v", err)
+	}
+	return u.String(), nil
+}
@@ -1,46 +1,68 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/joho/godotenv"
 )
 
+// defaultPresignTTL is the SigV4 maximum lifetime for a presigned URL.
+const defaultPresignTTL = 7 * 24 * time.Hour
+
 // S3Config holds AWS S3 configuration
 type S3Config struct {
-	Region          string
-	AccessKeyID     string
-	SecretAccessKey string
-	SessionToken    string
-	BucketName      string
+	Region           string
+	AccessKeyID      string
+	SecretAccessKey  string
+	SessionToken     string
+	BucketName       string
+	PresignTTL       time.Duration
+	AutoCreateBucket bool
 }
 
 // getS3Config returns S3 configuration from environment variables
 func getS3Config() *S3Config {
-	// Load .env.local file if it exists
+	loadEnvFiles()
+
+	presignTTL, err := time.ParseDuration(os.Getenv("AWS_S3_PRESIGN_TTL"))
+	if err != nil {
+		presignTTL = defaultPresignTTL
+	}
+
+	return &S3Config{
+		Region:           getEnvOrDefault("AWS_REGION", "us-east-1"),
+		AccessKeyID:      os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey:  os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:     os.Getenv("AWS_SESSION_TOKEN"),
+		BucketName:       getEnvOrDefault("AWS_S3_BUCKET", "whatsapp-stuff"),
+		PresignTTL:       presignTTL,
+		AutoCreateBucket: getEnvOrDefault("AWS_S3_AUTO_CREATE_BUCKET", "false") == "true",
+	}
+}
+
+// loadEnvFiles loads .env.local or .env into the process environment, if
+// present. When neither file exists it leaves the environment unchanged and
+// prints a notice.
+func loadEnvFiles() {
 	if err := godotenv.Load(".env.local"); err != nil {
-		// If .env.local doesn't exist, try .env
 		if err := godotenv.Load(".env"); err != nil {
-			// If neither exists, continue with environment variables
 			fmt.Printf("No .env.local or .env file found, using environment variables\n")
 		}
 	}
-
-	return &S3Config{
-		Region:          getEnvOrDefault("AWS_REGION", "us-east-1"),
-		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
-		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
-		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
-		BucketName:      getEnvOrDefault("AWS_S3_BUCKET", "whatsapp-stuff"),
-	}
 }
 
 // getEnvOrDefault returns environment variable value or default if not set
@@ -51,6 +73,20 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvIntOrDefault returns environment variable value parsed as an int, or
+// default if not set or unparseable.
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
 // createS3Bucket creates an S3 bucket if it doesn't exist
 func createS3Bucket(s3Client *s3.S3, bucketName string) error {
 	// Check if bucket already exists
@@ -81,54 +117,172 @@ func generateFolderPath(userID, chatJID string) string {
 	return folderPath
 }
 
-// uploadToS3 uploads a file to S3 and returns the S3 URL
-func uploadToS3(filePath, userID, chatJID string) (string, error) {
-	config := getS3Config()
-	bucketName := config.BucketName
+// s3Store is the MediaStore backend backed by AWS S3. The AWS session and
+// upload manager are expensive to build and are safe for concurrent use, so
+// they're constructed once per process and shared across uploads.
+type s3Store struct {
+	config *S3Config
+}
+
+// newS3Store builds the S3-backed MediaStore.
+func newS3Store() *s3Store {
+	return &s3Store{config: getS3Config()}
+}
+
+var (
+	s3SessionOnce sync.Once
+	s3Session     *session.Session
+	s3Uploader    *s3manager.Uploader
+	s3SessionErr  error
+)
+
+// getS3Session lazily builds the shared AWS session and s3manager.Uploader,
+// sized from AWS_S3_PART_SIZE_MB and AWS_S3_UPLOAD_CONCURRENCY so large
+// WhatsApp videos and voice notes upload as multipart transfers instead of
+// a single oversized PutObject.
+//
+// By default the session is built with the standard AWS credential chain
+// (env vars, shared config/credentials files, EC2/ECS instance metadata,
+// web identity tokens), so the bridge works unmodified on EC2/ECS/EKS with
+// an instance profile or IRSA. Static env-var credentials are only forced
+// when both AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are set explicitly,
+// which keeps local development working the way it always has.
+func getS3Session(config *S3Config) (*session.Session, *s3manager.Uploader, error) {
+	s3SessionOnce.Do(func() {
+		awsConfig := aws.NewConfig().WithRegion(config.Region)
+		if config.AccessKeyID != "" && config.SecretAccessKey != "" {
+			awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, config.SessionToken))
+		}
 
-	// Create AWS session
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(config.Region),
-		Credentials: credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, config.SessionToken),
+		s3Session, s3SessionErr = session.NewSessionWithOptions(session.Options{
+			Config:            *awsConfig,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if s3SessionErr != nil {
+			return
+		}
+
+		partSizeMB := getEnvIntOrDefault("AWS_S3_PART_SIZE_MB", 5)
+		concurrency := getEnvIntOrDefault("AWS_S3_UPLOAD_CONCURRENCY", 5)
+		s3Uploader = s3manager.NewUploader(s3Session, func(u *s3manager.Uploader) {
+			u.PartSize = int64(partSizeMB) * 1024 * 1024
+			u.Concurrency = concurrency
+		})
 	})
+	return s3Session, s3Uploader, s3SessionErr
+}
+
+func (s *s3Store) client() (*s3.S3, error) {
+	sess, _, err := getS3Session(s.config)
 	if err != nil {
-		return "", fmt.Errorf("failed to create AWS session: %v", err)
+		return nil, fmt.Errorf("failed to create AWS session: %v", err)
+	}
+	return s3.New(sess), nil
+}
+
+// Put streams r to the configured bucket under key using the shared
+// s3manager.Uploader, issuing a multipart upload for large files.
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (string, error) {
+	s3Client, err := s.client()
+	if err != nil {
+		return "", err
 	}
 
-	// Create S3 client
-	s3Client := s3.New(sess)
+	if s.config.AutoCreateBucket {
+		if err := createS3Bucket(s3Client, s.config.BucketName); err != nil {
+			return "", fmt.Errorf("failed to create S3 bucket: %v", err)
+		}
+	}
 
-	// Create bucket if it doesn't exist
-	if err := createS3Bucket(s3Client, bucketName); err != nil {
-		return "", fmt.Errorf("failed to create S3 bucket: %v", err)
+	_, uploader, err := getS3Session(s.config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create AWS session: %v", err)
 	}
 
-	// Read file
-	fileData, err := os.ReadFile(filePath)
+	_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:             aws.String(s.config.BucketName),
+		Key:                aws.String(key),
+		Body:               r,
+		ContentType:        aws.String(opts.ContentType),
+		ContentDisposition: aws.String(opts.ContentDisposition),
+		Metadata:           aws.StringMap(opts.Metadata),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %v", err)
+		return "", fmt.Errorf("failed to upload to S3: %v", err)
 	}
 
-	// Get filename from path
-	filename := filepath.Base(filePath)
+	return s.Presign(key, s.config.PresignTTL)
+}
 
-	// Generate folder path
-	folderPath := generateFolderPath(userID, chatJID)
+// Exists reports whether key is already present in the bucket.
+func (s *s3Store) Exists(ctx context.Context, key string) (bool, error) {
+	s3Client, err := s.client()
+	if err != nil {
+		return false, err
+	}
 
-	// Create full S3 key (folder path + filename)
-	s3Key := folderPath + filename
+	_, err = s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.config.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check for existing S3 object: %v", err)
+	}
+	return true, nil
+}
 
-	// Upload to S3
-	_, err = s3Client.PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(s3Key),
-		Body:   bytes.NewReader(fileData),
+// UpdateMetadata refreshes the content-type, content-disposition and
+// metadata of key in place via a same-bucket CopyObject with a REPLACE
+// metadata directive, without re-uploading the object's bytes.
+func (s *s3Store) UpdateMetadata(ctx context.Context, key string, opts PutOptions) error {
+	s3Client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:             aws.String(s.config.BucketName),
+		Key:                aws.String(key),
+		CopySource:         aws.String(s3CopySource(s.config.BucketName, key)),
+		MetadataDirective:  aws.String(s3.MetadataDirectiveReplace),
+		ContentType:        aws.String(opts.ContentType),
+		ContentDisposition: aws.String(opts.ContentDisposition),
+		Metadata:           aws.StringMap(opts.Metadata),
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %v", err)
+		return fmt.Errorf("failed to refresh S3 object metadata: %v", err)
 	}
+	return nil
+}
 
-	// Generate S3 URL
-	s3URL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, config.Region, s3Key)
-	return s3URL, nil
+// s3CopySource builds an x-amz-copy-source value, percent-encoding each path
+// segment of key but preserving the "/" separators.
+func s3CopySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.QueryEscape(seg)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
+
+// Presign returns a time-limited GET URL for key. A non-positive ttl falls
+// back to the configured AWS_S3_PRESIGN_TTL.
+func (s *s3Store) Presign(key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = s.config.PresignTTL
+	}
+
+	s3Client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+
+	req, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.config.BucketName),
+		Key:    aws.String(key),
+	})
+	return req.Presign(ttl)
 }
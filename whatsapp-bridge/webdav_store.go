@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/studio-b12/gowebdav"
+)
+
+// WebDAVConfig holds configuration for a WebDAV media backend.
+type WebDAVConfig struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// getWebDAVConfig returns WebDAV configuration from environment variables.
+func getWebDAVConfig() *WebDAVConfig {
+	loadEnvFiles()
+
+	return &WebDAVConfig{
+		URL:      os.Getenv("WEBDAV_URL"),
+		Username: os.Getenv("WEBDAV_USERNAME"),
+		Password: os.Getenv("WEBDAV_PASSWORD"),
+	}
+}
+
+// webdavStore is the MediaStore backend for a WebDAV server, e.g. Nextcloud
+// or a self-hosted nginx dav_ext share.
+type webdavStore struct {
+	config *WebDAVConfig
+	client *gowebdav.Client
+}
+
+// newWebDAVStore builds the WebDAV-backed MediaStore.
+func newWebDAVStore() (*webdavStore, error) {
+	config := getWebDAVConfig()
+	if config.URL == "" {
+		return nil, fmt.Errorf("WEBDAV_URL must be set when MEDIA_STORE=webdav")
+	}
+
+	return &webdavStore{
+		config: config,
+		client: gowebdav.NewClient(config.URL, config.Username, config.Password),
+	}, nil
+}
+
+// Put streams r to the WebDAV server under key. WriteStream creates any
+// missing parent collections itself. WebDAV has no notion of custom object
+// metadata, so opts.Metadata and opts.ContentDisposition are not persisted.
+func (w *webdavStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (string, error) {
+	if err := w.client.WriteStream(key, r, 0644); err != nil {
+		return "", fmt.Errorf("failed to upload to WebDAV: %v", err)
+	}
+
+	return strings.TrimRight(w.config.URL, "/") + "/" + key, nil
+}
+
+// Exists reports whether key is already present on the WebDAV server.
+func (w *webdavStore) Exists(ctx context.Context, key string) (bool, error) {
+	if _, err := w.client.Stat(key); err != nil {
+		if gowebdav.IsErrNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check for existing WebDAV object: %v", err)
+	}
+	return true, nil
+}
+
+// UpdateMetadata is a no-op: WebDAV has no concept of custom object
+// metadata, so a deduplicated upload keeps whatever chat-jid/sender/
+// message-id was recorded by the first upload to this key.
+func (w *webdavStore) UpdateMetadata(ctx context.Context, key string, opts PutOptions) error {
+	return nil
+}
+
+// Presign returns the plain WebDAV URL for key. WebDAV has no notion of
+// time-limited signed URLs, so access control is left to server auth.
+func (w *webdavStore) Presign(key string, ttl time.Duration) (string, error) {
+	return strings.TrimRight(w.config.URL, "/") + "/" + key, nil
+}